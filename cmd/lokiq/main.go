@@ -0,0 +1,303 @@
+// lokiq 是基于loki包查询接口的命令行工具
+// 支持范围查询、实时跟踪和标签查询，输出格式支持 raw、logfmt 和 json
+//
+// 用法：
+//
+//	lokiq [-addr URL] query [-since 1h] [-from T] [-to T] [-limit 100] [-forward] [-o raw|logfmt|json] '<logql>'
+//	lokiq [-addr URL] tail [-since 10s] [-interval 1s] [-lag 15s] [-o raw|logfmt|json] '<logql>'
+//	lokiq [-addr URL] labels [-since 1h] [name]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bt-smart/loki-client-go/loki"
+)
+
+// 支持的输出格式
+const (
+	formatRaw    = "raw"
+	formatLogfmt = "logfmt"
+	formatJSON   = "json"
+)
+
+// entry 表示一条展开后的日志，用于跨流排序和输出
+type entry struct {
+	// Timestamp 是日志的纳秒时间戳
+	Timestamp int64
+	// Labels 是日志所属流的标签
+	Labels map[string]string
+	// Line 是日志内容
+	Line string
+}
+
+func main() {
+	// 默认地址可以通过环境变量LOKI_ADDR设置
+	addr := os.Getenv("LOKI_ADDR")
+	if addr == "" {
+		addr = "http://localhost:3100"
+	}
+
+	flag.StringVar(&addr, "addr", addr, "Loki address, supports dns+ and dnssrv+ prefixes (env LOKI_ADDR)")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	// 收到中断信号时取消正在进行的请求
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client := loki.NewClient(loki.ClientConfig{URL: addr})
+
+	var err error
+	switch cmd, args := flag.Arg(0), flag.Args()[1:]; cmd {
+	case "query":
+		err = runQuery(ctx, client, args)
+	case "tail":
+		err = runTail(ctx, client, args)
+	case "labels":
+		err = runLabels(ctx, client, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "lokiq:", err)
+		os.Exit(1)
+	}
+}
+
+// usage 输出命令行帮助
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: lokiq [-addr URL] <command> [flags] [args]
+
+Commands:
+  query  '<logql>'  run a range query
+  tail   '<logql>'  follow new logs until interrupted
+  labels [name]     list label names, or values of a label
+
+Global flags:
+`)
+	flag.PrintDefaults()
+}
+
+// runQuery 执行范围查询并输出结果
+func runQuery(ctx context.Context, client *loki.Client, args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	since := fs.Duration("since", time.Hour, "look back this far from now, ignored when -from is set")
+	from := fs.String("from", "", "start time in RFC3339 format")
+	to := fs.String("to", "", "end time in RFC3339 format, defaults to now")
+	limit := fs.Int("limit", 100, "maximum number of entries to return")
+	forward := fs.Bool("forward", false, "return entries oldest first")
+	output := fs.String("o", formatRaw, "output format: raw, logfmt or json")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("query expects exactly one LogQL expression")
+	}
+	if err := checkFormat(*output); err != nil {
+		return err
+	}
+
+	start, end, err := timeRange(*since, *from, *to)
+	if err != nil {
+		return err
+	}
+
+	streams, err := client.QueryRange(ctx, loki.QueryRequest{
+		Query:   fs.Arg(0),
+		Start:   start,
+		End:     end,
+		Limit:   *limit,
+		Forward: *forward,
+	})
+	if err != nil {
+		return err
+	}
+	return printStreams(streams, *forward, *output)
+}
+
+// runTail 实时跟踪新日志，直到收到中断信号
+func runTail(ctx context.Context, client *loki.Client, args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	since := fs.Duration("since", 10*time.Second, "also print entries from this far back")
+	interval := fs.Duration("interval", time.Second, "polling interval")
+	lag := fs.Duration("lag", 15*time.Second, "re-query this far back on every poll to catch late-arriving entries")
+	output := fs.String("o", formatRaw, "output format: raw, logfmt or json")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("tail expects exactly one LogQL expression")
+	}
+	if err := checkFormat(*output); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+
+	if *lag < 0 {
+		return fmt.Errorf("lag must not be negative")
+	}
+
+	req := loki.TailRequest{
+		Query:    fs.Arg(0),
+		Start:    time.Now().Add(-*since),
+		Interval: *interval,
+		Lag:      *lag,
+	}
+	return client.Tail(ctx, req, func(streams []loki.Stream) error {
+		return printStreams(streams, true, *output)
+	})
+}
+
+// runLabels 输出标签名列表，指定标签名时输出该标签的取值列表
+func runLabels(ctx context.Context, client *loki.Client, args []string) error {
+	fs := flag.NewFlagSet("labels", flag.ExitOnError)
+	since := fs.Duration("since", time.Hour, "look back this far from now")
+	fs.Parse(args)
+
+	end := time.Now()
+	start := end.Add(-*since)
+
+	var (
+		values []string
+		err    error
+	)
+	switch fs.NArg() {
+	case 0:
+		values, err = client.LabelNames(ctx, start, end)
+	case 1:
+		values, err = client.LabelValues(ctx, fs.Arg(0), start, end)
+	default:
+		return fmt.Errorf("labels expects at most one label name")
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		fmt.Println(value)
+	}
+	return nil
+}
+
+// checkFormat 检查输出格式是否受支持
+func checkFormat(format string) error {
+	switch format {
+	case formatRaw, formatLogfmt, formatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// timeRange 根据命令行参数计算查询的时间范围
+func timeRange(since time.Duration, from, to string) (time.Time, time.Time, error) {
+	end := time.Now()
+	if to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("parse -to failed: %v", err)
+		}
+		end = t
+	}
+
+	start := end.Add(-since)
+	if from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("parse -from failed: %v", err)
+		}
+		start = t
+	}
+	return start, end, nil
+}
+
+// printStreams 将日志流展开为按时间排序的日志并输出
+// 参数：
+//   - streams: 查询到的日志流
+//   - forward: 为true时按时间正序输出，否则按时间倒序输出
+//   - format: 输出格式
+func printStreams(streams []loki.Stream, forward bool, format string) error {
+	var entries []entry
+	for _, stream := range streams {
+		for _, value := range stream.Values {
+			ts, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("parse timestamp %q failed: %v", value[0], err)
+			}
+			entries = append(entries, entry{Timestamp: ts, Labels: stream.Stream, Line: value[1]})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if forward {
+			return entries[i].Timestamp < entries[j].Timestamp
+		}
+		return entries[i].Timestamp > entries[j].Timestamp
+	})
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, e := range entries {
+		switch format {
+		case formatRaw:
+			fmt.Println(e.Line)
+		case formatLogfmt:
+			fmt.Println(logfmt(e))
+		case formatJSON:
+			if err := encoder.Encode(map[string]interface{}{
+				"timestamp": time.Unix(0, e.Timestamp).Format(time.RFC3339Nano),
+				"labels":    e.Labels,
+				"line":      e.Line,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// logfmt 将一条日志格式化为logfmt格式
+// 依次输出时间、按名称排序的标签和日志内容
+func logfmt(e entry) string {
+	names := make([]string, 0, len(e.Labels))
+	for name := range e.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("ts=")
+	b.WriteString(time.Unix(0, e.Timestamp).Format(time.RFC3339Nano))
+	for _, name := range names {
+		b.WriteString(" ")
+		b.WriteString(name)
+		b.WriteString("=")
+		b.WriteString(logfmtValue(e.Labels[name]))
+	}
+	b.WriteString(" line=")
+	b.WriteString(logfmtValue(e.Line))
+	return b.String()
+}
+
+// logfmtValue 在值为空或包含空格、等号、引号等字符时为其加上引号
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\t\r\n\\") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -3,8 +3,12 @@ package loki
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -22,6 +26,12 @@ type Client struct {
 	buffer *pkg.Buffer
 	// done 是用于优雅关闭的信号通道
 	done chan bool
+	// httpClient 是发送请求使用的HTTP客户端，复用底层连接
+	httpClient *http.Client
+	// discovery 负责将配置的地址解析为可用的推送端点
+	discovery *discovery
+	// limits 是服务器的有效限制，未开启FetchLimits时所有字段都为0
+	limits Limits
 }
 
 // NewClient 创建并初始化一个新的Loki客户端实例
@@ -47,12 +57,57 @@ func NewClient(config ClientConfig) *Client {
 	if config.MinLevel == 0 {
 		config.MinLevel = pkg.LevelInfo
 	}
+	// 设置默认的DNS重新解析间隔
+	if config.DiscoveryInterval == 0 {
+		config.DiscoveryInterval = 30 // 默认每30秒重新解析一次
+	}
+
+	d, err := newDiscovery(config.URL)
+	if err != nil {
+		// 地址格式错误时不创建任何端点，每次推送都会返回该错误
+		log.Println(err.Error())
+		d = &discovery{raw: config.URL, err: err}
+	}
+	// 首次解析失败不影响客户端创建，后台协程会继续重试
+	if err := d.refresh(); err != nil {
+		log.Println(err.Error())
+	}
+
+	// 使用独立的Transport，避免回收连接时影响全局的http.DefaultTransport
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// 端点URL中是解析得到的IP时，按配置的主机名校验证书
+	if name := d.serverName(); name != "" {
+		transport.TLSClientConfig = &tls.Config{ServerName: name}
+	}
+
+	c := &Client{
+		config:     config,
+		buffer:     pkg.NewBuffer(config.BatchSize),
+		done:       make(chan bool),
+		httpClient: &http.Client{Transport: transport},
+		discovery:  d,
+	}
 
-	return &Client{
-		config: config,
-		buffer: pkg.NewBuffer(config.BatchSize),
-		done:   make(chan bool),
+	// 依次尝试各个端点，使用第一个成功获取到的限制
+	if config.FetchLimits {
+		for _, endpoint := range d.pick() {
+			limits, err := c.fetchLimits(endpoint)
+			if err != nil {
+				log.Println(err.Error())
+				continue
+			}
+			c.applyLimits(limits)
+			break
+		}
 	}
+
+	return c
+}
+
+// Limits 返回从服务器获取到的有效限制
+// 未开启FetchLimits或获取失败时返回零值
+func (c *Client) Limits() Limits {
+	return c.limits
 }
 
 // Debug 记录调试级别的日志
@@ -85,13 +140,14 @@ func (c *Client) pushLogWithLevel(message string, level pkg.LogLevel) error {
 	// 创建日志条目，使用纳秒级时间戳
 	entry := pkg.LogEntry{
 		Timestamp: time.Now().UnixNano(),
-		Message:   message,
+		Message:   c.limits.truncateLine(message),
 		Level:     level,
 	}
 
-	// 添加到缓冲区，如果缓冲区已满则触发发送
+	// 添加到缓冲区，如果有段已写满则发送已写满的段
+	// 当前正在写入的段不受影响，其他goroutine可以继续写入
 	if c.buffer.Add(entry) {
-		c.flush()
+		c.flushFull()
 	}
 	return nil
 }
@@ -113,16 +169,43 @@ func (c *Client) Stop() {
 // 1. 定期检查是否需要发送日志
 // 2. 处理优雅关闭信号
 // 3. 确保日志不会在缓冲区中停留太久
+// 4. 定期回收长连接，使推送重新分散到健康的后端
+// 5. 定期重新解析DNS，更新可用的推送端点
 func (c *Client) worker() {
 	// 创建定时器，用于周期性检查是否需要发送日志
 	ticker := time.NewTicker(time.Second * time.Duration(c.config.MaxWaitTime))
+	defer ticker.Stop()
 	lastFlush := time.Now()
 
+	// 未配置连接最大存活时间时，recycle保持为nil，对应的case永远不会触发
+	var recycle <-chan time.Time
+	if c.config.MaxConnAge > 0 {
+		recycleTicker := time.NewTicker(time.Second * time.Duration(c.config.MaxConnAge))
+		defer recycleTicker.Stop()
+		recycle = recycleTicker.C
+	}
+
+	// 静态地址无需重新解析，resolve保持为nil
+	var resolve <-chan time.Time
+	if c.discovery.dynamic() {
+		resolveTicker := time.NewTicker(time.Second * time.Duration(c.config.DiscoveryInterval))
+		defer resolveTicker.Stop()
+		resolve = resolveTicker.C
+	}
+
 	for {
 		select {
 		case <-c.done:
 			// 收到关闭信号，退出工作协程
 			return
+		case <-recycle:
+			// 关闭所有空闲连接，下次请求时重新解析DNS并建立连接
+			// 正在使用中的连接不受影响，请求结束后会在下一个周期被回收
+			c.httpClient.CloseIdleConnections()
+		case <-resolve:
+			if err := c.discovery.refresh(); err != nil {
+				log.Println(err.Error())
+			}
 		case <-ticker.C:
 			// 检查是否超过最大等待时间
 			if time.Since(lastFlush) >= time.Second*time.Duration(c.config.MaxWaitTime) {
@@ -133,18 +216,40 @@ func (c *Client) worker() {
 	}
 }
 
-// flush 将缓冲区中的日志发送到Loki服务器
-// 主要步骤：
-// 1. 从缓冲区获取所有待发送的日志
-// 2. 将日志转换为Loki期望的格式
-// 3. 发送到服务器
+// flush 将缓冲区中的所有日志发送到Loki服务器
+// 每个段作为一次独立的推送发送
 func (c *Client) flush() {
 	// 获取并清空缓冲区
-	entries := c.buffer.Flush()
+	for _, segment := range c.buffer.FlushSegments() {
+		c.sendEntries(segment)
+	}
+}
+
+// flushFull 将缓冲区中已写满的段逐个发送到Loki服务器
+func (c *Client) flushFull() {
+	for segment := c.buffer.Next(); segment != nil; segment = c.buffer.Next() {
+		c.sendEntries(segment)
+	}
+}
+
+// sendEntries 将一段日志发送到Loki服务器
+// 超过服务器单次推送大小限制时拆分为多次推送
+func (c *Client) sendEntries(entries []pkg.LogEntry) {
 	if len(entries) == 0 {
 		return
 	}
 
+	for _, batch := range c.limits.splitEntries(entries) {
+		c.sendBatch(batch)
+	}
+}
+
+// sendBatch 将一批日志作为一次推送发送到Loki服务器
+// 主要步骤：
+// 1. 将日志按级别分组
+// 2. 将日志转换为Loki期望的格式
+// 3. 发送到服务器
+func (c *Client) sendBatch(entries []pkg.LogEntry) {
 	// 按日志级别分组
 	levelGroups := make(map[pkg.LogLevel][][2]string)
 	for _, entry := range entries {
@@ -163,7 +268,7 @@ func (c *Client) flush() {
 			labels[k] = v
 		}
 		// 添加日志级别标签
-		labels["detected_level"] = pkg.LevelToString(level)
+		labels[levelLabel] = pkg.LevelToString(level)
 
 		streams = append(streams, Stream{
 			Stream: labels,
@@ -183,7 +288,24 @@ func (c *Client) flush() {
 	}
 }
 
+// statusError 表示Loki服务器返回了非预期的状态码
+type statusError struct {
+	// code 是服务器返回的HTTP状态码
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.code)
+}
+
+// retryable 返回该错误换一个端点重试是否可能成功
+// 4xx（429除外）说明请求本身有问题，换端点也无法成功
+func (e *statusError) retryable() bool {
+	return e.code >= 500 || e.code == http.StatusTooManyRequests
+}
+
 // send 负责将日志请求发送到Loki服务器
+// 存在多个端点时按轮询顺序依次尝试，直到某个端点成功
 // 参数：
 //   - req: 要发送的日志请求
 //
@@ -196,16 +318,67 @@ func (c *Client) send(req PushRequest) error {
 		return fmt.Errorf("marshal request failed: %v", err)
 	}
 
+	endpoints := c.discovery.pick()
+	if len(endpoints) == 0 {
+		return c.discovery.unavailable()
+	}
+
+	// 配置了对冲延迟且有备用端点时，使用对冲请求降低尾延迟
+	if c.config.HedgeDelay > 0 && len(endpoints) > 1 {
+		return c.sendHedged(data, endpoints)
+	}
+
+	for _, endpoint := range endpoints {
+		err = c.post(context.Background(), endpoint, data)
+		if err == nil {
+			return nil
+		}
+		var se *statusError
+		if errors.As(err, &se) && !se.retryable() {
+			return err
+		}
+	}
+	return err
+}
+
+// post 向单个端点发送一次推送请求
+// 参数：
+//   - ctx: 请求的上下文，取消后请求会被中断
+//   - endpoint: 端点的基础URL
+//   - data: 序列化后的请求体
+//
+// 返回：
+//   - error: 发送过程中的错误，如果成功则为nil
+func (c *Client) post(ctx context.Context, endpoint string, data []byte) error {
+	req, err := c.newRequest(ctx, http.MethodPost, endpoint+"/loki/api/v1/push", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
 	// 发送HTTP POST请求
-	resp, err := http.Post(c.config.URL+"/loki/api/v1/push", "application/json", bytes.NewBuffer(data))
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("send request failed: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return &statusError{code: resp.StatusCode}
 	}
 
 	return nil
 }
+
+// newRequest 创建发往某个端点的HTTP请求
+// DNS方式解析得到IP时，会将Host头恢复为配置的主机名
+func (c *Client) newRequest(ctx context.Context, method, rawURL string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("create request failed: %v", err)
+	}
+	if host := c.discovery.hostHeader(); host != "" {
+		req.Host = host
+	}
+	return req, nil
+}
@@ -0,0 +1,274 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 地址前缀，用于指定基于DNS的端点发现方式
+const (
+	// dnsPrefix 表示通过A/AAAA记录解析主机名，适用于k8s headless service
+	// 例如：dns+http://loki-headless.monitoring.svc:3100
+	dnsPrefix = "dns+"
+	// dnsSRVPrefix 表示通过SRV记录解析主机和端口
+	// 例如：dnssrv+http://_http-metrics._tcp.loki.monitoring.svc
+	dnsSRVPrefix = "dnssrv+"
+)
+
+// resolveTimeout 是单次DNS解析的超时时间
+const resolveTimeout = 5 * time.Second
+
+// discovery 负责将配置的Loki地址解析为一组推送端点
+// 静态地址只包含一个端点；DNS地址会被周期性地重新解析
+type discovery struct {
+	// prefix 是地址的发现方式前缀，静态地址为空
+	prefix string
+	// target 是去掉前缀后的地址，DNS方式下其主机部分会被替换为解析结果
+	target *url.URL
+	// raw 是原始配置的地址
+	raw string
+	// err 是地址格式错误，不为nil时没有任何可用端点
+	err error
+
+	// mu 保护endpoints的并发访问
+	mu sync.RWMutex
+	// endpoints 是当前可用的端点列表，按优先级和URL排序
+	endpoints []endpoint
+
+	// next 是轮询计数器，用于在最高优先级的端点之间按权重负载均衡
+	next atomic.Uint32
+}
+
+// endpoint 表示一个解析得到的推送端点
+type endpoint struct {
+	// url 是端点的完整基础URL
+	url string
+	// priority 是SRV记录的优先级，数值越小越优先；A/AAAA记录和静态地址为0
+	priority uint16
+	// weight 是SRV记录的权重，同一优先级内按权重分配请求
+	weight uint16
+}
+
+// newDiscovery 根据配置的地址创建端点发现器
+// 参数：
+//   - rawURL: 配置的Loki地址，支持 http://、dns+http://、dnssrv+http:// 等形式
+//
+// 返回：
+//   - *discovery: 端点发现器，DNS方式需要调用refresh后才有可用端点
+//   - error: 地址格式错误时返回
+func newDiscovery(rawURL string) (*discovery, error) {
+	d := &discovery{raw: rawURL}
+
+	rest := rawURL
+	switch {
+	case strings.HasPrefix(rawURL, dnsSRVPrefix):
+		d.prefix = dnsSRVPrefix
+		rest = strings.TrimPrefix(rawURL, dnsSRVPrefix)
+	case strings.HasPrefix(rawURL, dnsPrefix):
+		d.prefix = dnsPrefix
+		rest = strings.TrimPrefix(rawURL, dnsPrefix)
+	default:
+		// 静态地址，保持原有行为
+		d.endpoints = []endpoint{{url: rawURL}}
+		return d, nil
+	}
+
+	target, err := url.Parse(rest)
+	if err != nil {
+		return nil, fmt.Errorf("parse url %q failed: %v", rawURL, err)
+	}
+	if target.Hostname() == "" {
+		return nil, fmt.Errorf("missing host in url %q", rawURL)
+	}
+	d.target = target
+	return d, nil
+}
+
+// dynamic 返回该地址是否需要周期性地重新解析
+func (d *discovery) dynamic() bool {
+	return d.prefix != ""
+}
+
+// refresh 重新解析DNS并更新端点列表
+// 解析失败或结果为空时保留上一次的端点列表，避免短暂的DNS故障导致无端点可用
+func (d *discovery) refresh() error {
+	if !d.dynamic() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	var (
+		endpoints []endpoint
+		err       error
+	)
+	if d.prefix == dnsSRVPrefix {
+		endpoints, err = d.resolveSRV(ctx)
+	} else {
+		endpoints, err = d.resolveHost(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("resolve %q failed: %v", d.raw, err)
+	}
+	if len(endpoints) == 0 {
+		return fmt.Errorf("resolve %q returned no endpoints", d.raw)
+	}
+
+	// 按优先级排序，同一优先级内按URL排序
+	// 保证DNS返回的顺序变化时，轮询计数器与端点的对应关系仍然稳定
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].priority != endpoints[j].priority {
+			return endpoints[i].priority < endpoints[j].priority
+		}
+		return endpoints[i].url < endpoints[j].url
+	})
+	d.mu.Lock()
+	d.endpoints = endpoints
+	d.mu.Unlock()
+	return nil
+}
+
+// resolveHost 通过A/AAAA记录解析主机名，端口沿用地址中配置的端口
+func (d *discovery) resolveHost(ctx context.Context) ([]endpoint, error) {
+	addrs, err := net.DefaultResolver.LookupHost(ctx, d.target.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	port := d.target.Port()
+	endpoints := make([]endpoint, 0, len(addrs))
+	for _, addr := range addrs {
+		endpoints = append(endpoints, endpoint{url: d.endpointURL(addr, port)})
+	}
+	return endpoints, nil
+}
+
+// resolveSRV 通过SRV记录解析主机和端口，并保留每条记录的优先级和权重
+func (d *discovery) resolveSRV(ctx context.Context) ([]endpoint, error) {
+	// service和proto为空时直接查询完整的记录名，如 _http._tcp.loki.svc
+	_, srvs, err := net.DefaultResolver.LookupSRV(ctx, "", "", d.target.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]endpoint, 0, len(srvs))
+	for _, srv := range srvs {
+		host := strings.TrimSuffix(srv.Target, ".")
+		endpoints = append(endpoints, endpoint{
+			url:      d.endpointURL(host, strconv.Itoa(int(srv.Port))),
+			priority: srv.Priority,
+			weight:   srv.Weight,
+		})
+	}
+	return endpoints, nil
+}
+
+// hostHeader 返回请求应使用的Host头，为空时使用端点URL中的主机
+// A/AAAA方式下端点URL中是解析得到的IP，需要保留配置的主机名，
+// 否则按Host头路由的网关无法识别请求
+// SRV方式下端点URL中是记录的目标主机名，直接使用即可
+func (d *discovery) hostHeader() string {
+	if d.prefix != dnsPrefix {
+		return ""
+	}
+	return d.target.Host
+}
+
+// serverName 返回TLS握手时应校验的服务器名称，为空时使用端点URL中的主机
+// A/AAAA方式下端点URL中是IP，需要按配置的主机名进行SNI和证书校验
+func (d *discovery) serverName() string {
+	if d.prefix != dnsPrefix || d.target.Scheme != "https" {
+		return ""
+	}
+	return d.target.Hostname()
+}
+
+// endpointURL 使用解析得到的主机和端口替换目标地址的主机部分，生成完整的基础URL
+func (d *discovery) endpointURL(host, port string) string {
+	u := *d.target
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6地址在URL中需要用方括号包裹
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+	return u.String()
+}
+
+// unavailable 返回没有可用端点时的错误
+// 地址格式错误时返回具体的错误原因，便于排查配置问题
+func (d *discovery) unavailable() error {
+	if d.err != nil {
+		return fmt.Errorf("no available endpoint: %v", d.err)
+	}
+	return fmt.Errorf("no available endpoint for %s", d.raw)
+}
+
+// pick 返回本次请求应依次尝试的端点列表
+// 主要规则：
+// 1. 起始端点从优先级数值最小的一组中按权重轮询选择
+// 2. 同组的其余端点紧随其后
+// 3. 优先级较低的组按优先级排在最后，只在前面的端点都失败时使用
+func (d *discovery) pick() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if len(d.endpoints) == 0 {
+		return nil
+	}
+
+	// 找出优先级最高的一组，endpoints已按优先级排序
+	n := 1
+	for n < len(d.endpoints) && d.endpoints[n].priority == d.endpoints[0].priority {
+		n++
+	}
+	first := d.endpoints[:n]
+
+	// 计算组内的总权重，所有权重都为0时视为等权
+	var total uint32
+	for _, e := range first {
+		total += uint32(e.weight)
+	}
+	equal := total == 0
+	if equal {
+		total = uint32(n)
+	}
+	weight := func(e endpoint) uint32 {
+		if equal {
+			return 1
+		}
+		return uint32(e.weight)
+	}
+
+	// 将计数器映射到累计权重区间，得到起始端点
+	// 先在uint32上取模，避免32位平台上计数器超过2^31后转换为负数
+	slot := (d.next.Add(1) - 1) % total
+	start := 0
+	for i, e := range first {
+		if slot < weight(e) {
+			start = i
+			break
+		}
+		slot -= weight(e)
+	}
+
+	ordered := make([]string, 0, len(d.endpoints))
+	for i := 0; i < n; i++ {
+		ordered = append(ordered, first[(start+i)%n].url)
+	}
+	for _, e := range d.endpoints[n:] {
+		ordered = append(ordered, e.url)
+	}
+	return ordered
+}
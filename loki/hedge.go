@@ -0,0 +1,72 @@
+package loki
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// sendHedged 使用对冲请求发送推送
+// 主要步骤：
+// 1. 向第一个端点发送请求
+// 2. 超过对冲延迟仍未完成时，向下一个端点再发送一次相同的请求
+// 3. 以先成功的请求为准，并取消其余仍在进行中的请求
+// 4. 请求失败时立即切换到下一个端点，不再等待对冲延迟
+//
+// 同一批日志可能被多个端点同时接收，Loki会对时间戳和内容都相同的日志去重
+// 参数：
+//   - data: 序列化后的请求体
+//   - endpoints: 按尝试顺序排列的端点，至少包含两个
+//
+// 返回：
+//   - error: 所有请求都失败时返回最后一个错误，如果成功则为nil
+func (c *Client) sendHedged(data []byte, endpoints []string) error {
+	// 函数返回时取消所有仍在进行中的请求
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 缓冲区足够容纳所有请求的结果，被取消的请求不会阻塞在发送结果上
+	results := make(chan error, len(endpoints))
+	launched := 0
+	launch := func() {
+		endpoint := endpoints[launched]
+		launched++
+		go func() {
+			results <- c.post(ctx, endpoint, data)
+		}()
+	}
+
+	launch()
+	pending := 1
+
+	// 只发送一次对冲请求，避免延迟抖动时成倍放大服务器压力
+	hedge := time.NewTimer(time.Millisecond * time.Duration(c.config.HedgeDelay))
+	defer hedge.Stop()
+
+	var lastErr error
+	for pending > 0 {
+		select {
+		case err := <-results:
+			pending--
+			if err == nil {
+				return nil
+			}
+			lastErr = err
+			var se *statusError
+			if errors.As(err, &se) && !se.retryable() {
+				return err
+			}
+			// 请求失败时立即尝试下一个端点
+			if launched < len(endpoints) {
+				launch()
+				pending++
+			}
+		case <-hedge.C:
+			if launched < len(endpoints) {
+				launch()
+				pending++
+			}
+		}
+	}
+	return lastErr
+}
@@ -0,0 +1,250 @@
+package loki
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bt-smart/loki-client-go/pkg"
+)
+
+// fetchLimitsTimeout 是获取服务器限制的超时时间
+const fetchLimitsTimeout = 10 * time.Second
+
+// levelLabel 是客户端为每个流自动添加的日志级别标签
+const levelLabel = "detected_level"
+
+// entryOverhead 是估算推送大小时每条日志额外占用的字节数
+// 包括时间戳字符串和JSON中的引号、逗号等
+const entryOverhead = 32
+
+// Limits 表示Loki服务器对日志写入的有效限制
+// 取值为0表示服务器未设置该限制
+type Limits struct {
+	// MaxLineSize 是单条日志的最大字节数，对应 limits_config.max_line_size
+	MaxLineSize int
+	// MaxLabelNamesPerSeries 是每个流的最大标签数，对应 limits_config.max_label_names_per_series
+	MaxLabelNamesPerSeries int
+	// MaxStreams 是每个租户的最大活跃流数量，对应 limits_config.max_global_streams_per_user
+	MaxStreams int
+	// MaxPushBytes 是单次推送的最大字节数，对应 limits_config.ingestion_burst_size_mb
+	MaxPushBytes int
+}
+
+// fetchLimits 从Loki服务器的 /config 接口获取有效限制
+// 参数：
+//   - endpoint: 端点的基础URL
+//
+// 返回：
+//   - Limits: 解析得到的限制
+//   - error: 请求或解析过程中的错误
+func (c *Client) fetchLimits(endpoint string) (Limits, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchLimitsTimeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, http.MethodGet, endpoint+"/config", nil)
+	if err != nil {
+		return Limits{}, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Limits{}, fmt.Errorf("fetch limits failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Limits{}, &statusError{code: resp.StatusCode}
+	}
+
+	return parseLimits(resp.Body)
+}
+
+// parseLimits 从 /config 接口返回的YAML中解析 limits_config 部分
+// 只需要少量的标量字段，因此按行解析，避免引入YAML依赖
+// 只读取 limits_config 的直接子键，嵌套在其下的映射和列表会被忽略
+func parseLimits(r io.Reader) (Limits, error) {
+	var (
+		limits  Limits
+		section bool
+		// indent 是 limits_config 直接子键的缩进，由段内第一个键确定
+		indent int
+		err    error
+	)
+
+	scanner := bufio.NewScanner(r)
+	// 完整配置中可能包含很长的行，放宽单行长度限制
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		// 顶层的键标志着一个新的配置段
+		if line[0] != ' ' {
+			section = strings.TrimSpace(line) == "limits_config:"
+			indent = 0
+			continue
+		}
+		if !section {
+			continue
+		}
+
+		// 缩进更深的行属于嵌套的映射或列表，不是 limits_config 的直接子键
+		n := len(line) - len(strings.TrimLeft(line, " "))
+		if indent == 0 {
+			indent = n
+		}
+		if n != indent {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		// 去掉行尾注释和引号
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+
+		switch key {
+		case "max_line_size":
+			limits.MaxLineSize, err = parseByteSize(value)
+		case "max_label_names_per_series":
+			limits.MaxLabelNamesPerSeries, err = strconv.Atoi(value)
+		case "max_global_streams_per_user":
+			limits.MaxStreams, err = strconv.Atoi(value)
+		case "ingestion_burst_size_mb":
+			var mb float64
+			mb, err = strconv.ParseFloat(value, 64)
+			limits.MaxPushBytes = int(mb * (1 << 20))
+		}
+		if err != nil {
+			return Limits{}, fmt.Errorf("parse %s failed: %v", key, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Limits{}, fmt.Errorf("read config failed: %v", err)
+	}
+
+	return limits, nil
+}
+
+// parseByteSize 解析Loki配置中的字节大小，如 "256KB"、"1MiB"、"0"
+// Loki按1024进制处理KB、MB等单位
+func parseByteSize(value string) (int, error) {
+	value = strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	number, unit := value, ""
+	if i >= 0 {
+		number, unit = value[:i], value[i:]
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	switch unit {
+	case "", "B":
+		return int(n), nil
+	case "K", "KB", "KIB":
+		return int(n * (1 << 10)), nil
+	case "M", "MB", "MIB":
+		return int(n * (1 << 20)), nil
+	case "G", "GB", "GIB":
+		return int(n * (1 << 30)), nil
+	default:
+		return 0, fmt.Errorf("unknown unit %q", unit)
+	}
+}
+
+// truncateLine 将日志截断到服务器允许的最大长度
+// 截断位置会回退到完整的UTF-8字符边界
+func (l Limits) truncateLine(message string) string {
+	if l.MaxLineSize <= 0 || len(message) <= l.MaxLineSize {
+		return message
+	}
+	end := l.MaxLineSize
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return message[:end]
+}
+
+// trimLabels 裁剪默认标签，使加上日志级别标签后不超过服务器允许的标签数
+// 按标签名排序后保留靠前的标签，保证多次调用结果一致
+// 返回：
+//   - map[string]string: 裁剪后的标签，不会修改传入的map
+//   - []string: 被丢弃的标签名
+func (l Limits) trimLabels(labels map[string]string) (map[string]string, []string) {
+	// 为日志级别标签预留一个位置
+	keep := l.MaxLabelNamesPerSeries - 1
+	if l.MaxLabelNamesPerSeries <= 0 || len(labels) <= keep {
+		return labels, nil
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	trimmed := make(map[string]string, keep)
+	for _, name := range names[:keep] {
+		trimmed[name] = labels[name]
+	}
+	return trimmed, names[keep:]
+}
+
+// splitEntries 将一段日志拆分为多个批次，使每次推送的估算大小不超过服务器限制
+// 单条日志超过限制时单独成为一个批次
+func (l Limits) splitEntries(entries []pkg.LogEntry) [][]pkg.LogEntry {
+	if l.MaxPushBytes <= 0 {
+		return [][]pkg.LogEntry{entries}
+	}
+
+	var (
+		batches [][]pkg.LogEntry
+		start   int
+		size    int
+	)
+	for i, entry := range entries {
+		n := len(entry.Message) + entryOverhead
+		if size+n > l.MaxPushBytes && i > start {
+			batches = append(batches, entries[start:i])
+			start, size = i, 0
+		}
+		size += n
+	}
+	return append(batches, entries[start:])
+}
+
+// applyLimits 根据服务器的限制调整客户端行为，并记录需要注意的配置问题
+func (c *Client) applyLimits(limits Limits) {
+	c.limits = limits
+
+	labels, dropped := limits.trimLabels(c.config.Labels)
+	if len(dropped) > 0 {
+		log.Printf("labels %v dropped: server allows at most %d label names per series", dropped, limits.MaxLabelNamesPerSeries)
+	}
+	c.config.Labels = labels
+
+	// 每个日志级别对应一个流
+	if streams := int(pkg.LevelError-pkg.LevelDebug) + 1; limits.MaxStreams > 0 && limits.MaxStreams < streams {
+		log.Printf("server allows at most %d streams, client may create up to %d", limits.MaxStreams, streams)
+	}
+}
@@ -0,0 +1,286 @@
+package loki
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bt-smart/loki-client-go/pkg"
+)
+
+// configExcerpt 是Loki 3.x /config 接口返回内容的节选
+// 保留了 limits_config 前后的配置段，以及其下嵌套的映射和列表
+const configExcerpt = `target: all
+auth_enabled: false
+server:
+  http_listen_address: ""
+  http_listen_port: 3100
+  grpc_server_max_recv_msg_size: 4194304
+distributor:
+  ring:
+    kvstore:
+      store: inmemory
+  max_recv_msg_size: 104857600
+limits_config:
+  ingestion_rate_strategy: global
+  ingestion_rate_mb: 4
+  ingestion_burst_size_mb: 6
+  max_label_name_length: 1024
+  max_label_value_length: 2048
+  max_label_names_per_series: 15
+  reject_old_samples: true
+  reject_old_samples_max_age: 1w
+  creation_grace_period: 10m
+  max_line_size: 256KB
+  max_line_size_truncate: false
+  increment_duplicate_timestamp: false
+  discover_service_name:
+  - service
+  - app
+  - application
+  discover_log_levels: true
+  max_streams_per_user: 0
+  max_global_streams_per_user: 5000
+  unordered_writes: true
+  shard_streams:
+    enabled: true
+    logging_enabled: false
+    desired_rate: 1536KB
+  otlp_config:
+    resource_attributes:
+      attributes_config:
+      - action: index_label
+        attributes:
+        - service.name
+querier:
+  max_concurrent: 4
+frontend:
+  max_outstanding_per_tenant: 2048
+`
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    Limits
+		wantErr bool
+	}{
+		{
+			name:   "loki config excerpt",
+			config: configExcerpt,
+			want: Limits{
+				MaxLineSize:            256 << 10,
+				MaxLabelNamesPerSeries: 15,
+				MaxStreams:             5000,
+				MaxPushBytes:           6 << 20,
+			},
+		},
+		{
+			name: "quoted values and comments",
+			config: `limits_config:
+  max_line_size: "1MiB"
+  max_label_names_per_series: '20' # per series
+  ingestion_burst_size_mb: 0.5
+`,
+			want: Limits{
+				MaxLineSize:            1 << 20,
+				MaxLabelNamesPerSeries: 20,
+				MaxPushBytes:           1 << 19,
+			},
+		},
+		{
+			name: "zero means unlimited",
+			config: `limits_config:
+  max_line_size: 0
+  max_global_streams_per_user: 0
+`,
+			want: Limits{},
+		},
+		{
+			name: "nested keys are ignored",
+			config: `limits_config:
+  max_line_size: 100B
+  shard_streams:
+    max_line_size: 1MB
+    max_global_streams_per_user: 1
+`,
+			want: Limits{MaxLineSize: 100},
+		},
+		{
+			name: "keys outside limits_config are ignored",
+			config: `server:
+  max_line_size: 1MB
+limits_config:
+  max_label_names_per_series: 10
+runtime_config:
+  max_line_size: 2MB
+`,
+			want: Limits{MaxLabelNamesPerSeries: 10},
+		},
+		{
+			name:   "missing limits_config",
+			config: "server:\n  http_listen_port: 3100\n",
+			want:   Limits{},
+		},
+		{
+			name:    "invalid value",
+			config:  "limits_config:\n  max_label_names_per_series: many\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLimits(strings.NewReader(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseLimits() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "0", want: 0},
+		{value: "512", want: 512},
+		{value: "10B", want: 10},
+		{value: "256KB", want: 256 << 10},
+		{value: "256 kB", want: 256 << 10},
+		{value: "1MiB", want: 1 << 20},
+		{value: "1.5MB", want: 3 << 19},
+		{value: "2GB", want: 2 << 30},
+		{value: "KB", wantErr: true},
+		{value: "5XB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseByteSize(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseByteSize(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseByteSize(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		max     int
+		message string
+		want    string
+	}{
+		{name: "unlimited", max: 0, message: "hello world", want: "hello world"},
+		{name: "shorter", max: 20, message: "hello world", want: "hello world"},
+		{name: "exact", max: 5, message: "hello", want: "hello"},
+		{name: "ascii", max: 5, message: "hello world", want: "hello"},
+		// "日志" 每个字符占3个字节，截断位置落在第二个字符中间时回退到其起始位置
+		{name: "utf-8 boundary", max: 4, message: "日志", want: "日"},
+		{name: "utf-8 exact", max: 6, message: "日志内容", want: "日志"},
+		{name: "shorter than first rune", max: 2, message: "日志", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Limits{MaxLineSize: tt.max}.truncateLine(tt.message)
+			if got != tt.want {
+				t.Errorf("truncateLine(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimLabels(t *testing.T) {
+	labels := map[string]string{"service_name": "a", "env": "prod", "host": "h1"}
+
+	tests := []struct {
+		name        string
+		max         int
+		want        map[string]string
+		wantDropped []string
+	}{
+		{name: "unlimited", max: 0, want: labels},
+		{name: "within limit", max: 4, want: labels},
+		{
+			name:        "trimmed by name",
+			max:         3,
+			want:        map[string]string{"env": "prod", "host": "h1"},
+			wantDropped: []string{"service_name"},
+		},
+		{
+			name:        "only level label fits",
+			max:         1,
+			want:        map[string]string{},
+			wantDropped: []string{"env", "host", "service_name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, dropped := Limits{MaxLabelNamesPerSeries: tt.max}.trimLabels(labels)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trimLabels() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(dropped, tt.wantDropped) {
+				t.Errorf("trimLabels() dropped = %v, want %v", dropped, tt.wantDropped)
+			}
+		})
+	}
+	if len(labels) != 3 {
+		t.Errorf("trimLabels() modified the input labels: %v", labels)
+	}
+}
+
+func TestSplitEntries(t *testing.T) {
+	// entries 构造若干条消息长度为n的日志，每条估算大小为 n+entryOverhead
+	entries := func(sizes ...int) []pkg.LogEntry {
+		result := make([]pkg.LogEntry, 0, len(sizes))
+		for _, n := range sizes {
+			result = append(result, pkg.LogEntry{Message: strings.Repeat("x", n)})
+		}
+		return result
+	}
+	// sizes 返回每个批次中各条日志的消息长度
+	sizes := func(batches [][]pkg.LogEntry) [][]int {
+		result := make([][]int, 0, len(batches))
+		for _, batch := range batches {
+			var batchSizes []int
+			for _, entry := range batch {
+				batchSizes = append(batchSizes, len(entry.Message))
+			}
+			result = append(result, batchSizes)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name    string
+		max     int
+		entries []pkg.LogEntry
+		want    [][]int
+	}{
+		{name: "unlimited", max: 0, entries: entries(100, 100, 100), want: [][]int{{100, 100, 100}}},
+		{name: "fits in one batch", max: 3 * (10 + entryOverhead), entries: entries(10, 10, 10), want: [][]int{{10, 10, 10}}},
+		{name: "split", max: 2 * (10 + entryOverhead), entries: entries(10, 10, 10, 10, 10), want: [][]int{{10, 10}, {10, 10}, {10}}},
+		{name: "oversized entry alone", max: 50, entries: entries(10, 100, 10), want: [][]int{{10}, {100}, {10}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sizes(Limits{MaxPushBytes: tt.max}.splitEntries(tt.entries))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitEntries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
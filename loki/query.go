@@ -0,0 +1,268 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tailLimit 是实时跟踪时每次轮询最多获取的日志条数
+const tailLimit = 1000
+
+// QueryRange 在指定的时间范围内查询日志
+// 参数：
+//   - ctx: 请求的上下文
+//   - req: 查询参数
+//
+// 返回：
+//   - []Stream: 查询到的日志流
+//   - error: 查询过程中的错误，如果成功则为nil
+func (c *Client) QueryRange(ctx context.Context, req QueryRequest) ([]Stream, error) {
+	params := url.Values{}
+	params.Set("query", req.Query)
+	params.Set("start", strconv.FormatInt(req.Start.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(req.End.UnixNano(), 10))
+	if req.Limit > 0 {
+		params.Set("limit", strconv.Itoa(req.Limit))
+	}
+	if req.Forward {
+		params.Set("direction", "forward")
+	} else {
+		params.Set("direction", "backward")
+	}
+
+	var resp queryResponse
+	if err := c.get(ctx, "/loki/api/v1/query_range", params, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Data.ResultType != "streams" {
+		return nil, fmt.Errorf("unsupported result type: %s", resp.Data.ResultType)
+	}
+	return resp.Data.Result, nil
+}
+
+// LabelNames 查询指定时间范围内出现过的所有标签名
+func (c *Client) LabelNames(ctx context.Context, start, end time.Time) ([]string, error) {
+	params := url.Values{}
+	params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+
+	var resp labelsResponse
+	if err := c.get(ctx, "/loki/api/v1/labels", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// LabelValues 查询指定时间范围内某个标签的所有取值
+func (c *Client) LabelValues(ctx context.Context, name string, start, end time.Time) ([]string, error) {
+	params := url.Values{}
+	params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+
+	var resp labelsResponse
+	if err := c.get(ctx, "/loki/api/v1/label/"+url.PathEscape(name)+"/values", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// Tail 实时跟踪匹配查询语句的日志，直到ctx被取消
+// 通过周期性地查询实现，不依赖websocket
+// 主要步骤：
+// 1. 每次轮询都从最近Lag时间之前开始查询，覆盖滞后到达的日志
+// 2. 按（流标签、时间戳、日志内容）去重，只把新出现的日志交给handler
+// 3. 结果被limit截断时，下次从截断处继续查询
+//
+// 参数：
+//   - ctx: 取消后停止跟踪
+//   - req: 跟踪参数
+//   - handler: 每次查询到新日志时调用，返回错误时停止跟踪
+//
+// 返回：
+//   - error: 查询或handler返回的错误，ctx被取消时返回nil
+func (c *Client) Tail(ctx context.Context, req TailRequest, handler func([]Stream) error) error {
+	interval := req.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// from 之前的日志已经不会再被查询
+	from := req.Start
+	// seen 记录from之后已输出过的日志及其时间戳，用于去重和过期清理
+	seen := make(map[string]int64)
+
+	for {
+		end := time.Now()
+		streams, err := c.QueryRange(ctx, QueryRequest{
+			Query:   req.Query,
+			Start:   from,
+			End:     end,
+			Limit:   tailLimit,
+			Forward: true,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if fresh := unseen(streams, seen); len(fresh) > 0 {
+			if err := handler(fresh); err != nil {
+				return err
+			}
+		}
+
+		// 下次查询保留Lag时间的回看窗口
+		next := end.Add(-req.Lag)
+		// 结果被limit截断时，最后一条之后的日志还没有查询，不能越过它
+		if count(streams) >= tailLimit {
+			if last := latest(streams); last.Before(next) {
+				next = last
+			}
+		}
+		if next.After(from) {
+			from = next
+			// 早于from的日志不会再被查询到，无需继续记录
+			for key, ts := range seen {
+				if ts < from.UnixNano() {
+					delete(seen, key)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// unseen 过滤掉已经输出过的日志，并将新日志记录到seen中
+// 返回：
+//   - []Stream: 只包含新日志的流，没有新日志的流会被省略
+func unseen(streams []Stream, seen map[string]int64) []Stream {
+	var fresh []Stream
+	for _, stream := range streams {
+		labels := labelsKey(stream.Stream)
+		var values [][2]string
+		for _, value := range stream.Values {
+			key := labels + "\x00" + value[0] + "\x00" + value[1]
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			ts, _ := strconv.ParseInt(value[0], 10, 64)
+			seen[key] = ts
+			values = append(values, value)
+		}
+		if len(values) > 0 {
+			fresh = append(fresh, Stream{Stream: stream.Stream, Values: values})
+		}
+	}
+	return fresh
+}
+
+// labelsKey 将流标签按名称排序后拼接为字符串，用于唯一标识一个流
+func labelsKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteString("=")
+		b.WriteString(strconv.Quote(labels[name]))
+		b.WriteString(",")
+	}
+	return b.String()
+}
+
+// count 返回日志流中的日志总条数
+func count(streams []Stream) int {
+	n := 0
+	for _, stream := range streams {
+		n += len(stream.Values)
+	}
+	return n
+}
+
+// latest 返回日志流中最新一条日志的时间
+func latest(streams []Stream) time.Time {
+	var newest int64
+	for _, stream := range streams {
+		for _, value := range stream.Values {
+			ts, err := strconv.ParseInt(value[0], 10, 64)
+			if err == nil && ts > newest {
+				newest = ts
+			}
+		}
+	}
+	return time.Unix(0, newest)
+}
+
+// get 向Loki服务器发送查询请求并解析JSON响应
+// 存在多个端点时按轮询顺序依次尝试，直到某个端点成功
+// 参数：
+//   - ctx: 请求的上下文
+//   - path: 接口路径
+//   - params: 查询参数
+//   - out: 用于接收响应的结构体指针
+//
+// 返回：
+//   - error: 查询过程中的错误，如果成功则为nil
+func (c *Client) get(ctx context.Context, path string, params url.Values, out interface{}) error {
+	endpoints := c.discovery.pick()
+	if len(endpoints) == 0 {
+		return c.discovery.unavailable()
+	}
+
+	var err error
+	for _, endpoint := range endpoints {
+		err = c.getFrom(ctx, endpoint+path+"?"+params.Encode(), out)
+		if err == nil {
+			return nil
+		}
+		var se *statusError
+		if ctx.Err() != nil || (errors.As(err, &se) && !se.retryable()) {
+			return err
+		}
+	}
+	return err
+}
+
+// getFrom 向单个端点发送一次查询请求
+func (c *Client) getFrom(ctx context.Context, rawURL string, out interface{}) error {
+	req, err := c.newRequest(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{code: resp.StatusCode}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response failed: %v", err)
+	}
+	return nil
+}
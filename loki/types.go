@@ -1,6 +1,10 @@
 package loki
 
-import "github.com/bt-smart/loki-client-go/pkg"
+import (
+	"time"
+
+	"github.com/bt-smart/loki-client-go/pkg"
+)
 
 // Stream 表示一个日志流
 // 包含流的标签信息和具体的日志内容
@@ -19,9 +23,66 @@ type PushRequest struct {
 	Streams []Stream `json:"streams"`
 }
 
+// QueryRequest 定义一次日志范围查询的参数
+type QueryRequest struct {
+	// Query 是LogQL日志查询语句，如 {service_name="myapp"} |= "error"
+	Query string
+	// Start 是查询的起始时间（包含）
+	Start time.Time
+	// End 是查询的结束时间（不包含）
+	End time.Time
+	// Limit 是最多返回的日志条数，为0时使用服务器的默认值
+	Limit int
+	// Forward 为true时按时间正序返回，否则按时间倒序返回
+	Forward bool
+}
+
+// TailRequest 定义实时跟踪日志的参数
+type TailRequest struct {
+	// Query 是LogQL日志查询语句
+	Query string
+	// Start 是开始跟踪的时间，早于该时间的日志不会输出
+	Start time.Time
+	// Interval 是轮询间隔，为0时默认1秒
+	Interval time.Duration
+	// Lag 是每次轮询时回看的时间窗口
+	// 日志的时间戳在写入时生成，推送到Loki可能滞后（本客户端默认最多滞后MaxWaitTime）
+	// 每次轮询都会重新查询最近Lag时间内的日志，已输出过的日志会被去重
+	// 滞后超过Lag的日志不会被输出
+	Lag time.Duration
+}
+
+// queryResponse 表示 /loki/api/v1/query_range 接口的响应
+type queryResponse struct {
+	// Status 是请求状态，成功时为 "success"
+	Status string `json:"status"`
+	// Data 包含查询结果
+	Data struct {
+		// ResultType 是结果类型，日志查询为 "streams"
+		ResultType string `json:"resultType"`
+		// Result 是查询到的日志流
+		Result []Stream `json:"result"`
+	} `json:"data"`
+}
+
+// labelsResponse 表示标签名和标签值接口的响应
+type labelsResponse struct {
+	// Status 是请求状态，成功时为 "success"
+	Status string `json:"status"`
+	// Data 是标签名或标签值列表
+	Data []string `json:"data"`
+}
+
 // ClientConfig 定义Loki客户端的配置参数
 type ClientConfig struct {
 	// URL 是Loki服务器的地址
+	// 除静态地址外，还支持基于DNS的端点发现：
+	//   - dns+http://host:port: 解析主机名的A/AAAA记录，适用于headless service
+	//     请求直接发往解析得到的IP，Host头和https的证书校验仍使用配置的主机名
+	//   - dnssrv+http://_service._proto.name: 解析SRV记录得到主机和端口
+	//     请求发往SRV记录的目标主机，Host头和证书校验使用目标主机名
+	// 解析出多个端点时，推送会在端点之间轮询，失败时自动切换到下一个端点
+	// SRV记录按优先级分组，只在优先级最高的一组内按权重轮询，其余组仅用于故障转移
 	URL string
 	// Labels 定义默认的标签集
 	Labels map[string]string
@@ -33,4 +94,19 @@ type ClientConfig struct {
 	MaxWaitTime int64
 	// MinLevel 定义最低日志级别，低于此级别的日志将被忽略
 	MinLevel pkg.LogLevel
+	// MaxConnAge 定义keep-alive连接的最大存活时间（秒）
+	// 到期后关闭空闲连接，下次推送时重新解析DNS并建立新连接
+	// 为0时不主动回收连接
+	MaxConnAge int64
+	// DiscoveryInterval 定义DNS端点重新解析的间隔（秒）
+	// 仅在URL使用dns+或dnssrv+前缀时生效
+	DiscoveryInterval int64
+	// HedgeDelay 定义对冲请求的延迟（毫秒）
+	// 推送在该时间内未完成时，向另一个端点再发送一次相同的请求，以先成功的为准
+	// 为0或只有一个端点时不发送对冲请求
+	HedgeDelay int64
+	// FetchLimits 定义是否在创建客户端时获取服务器的有效限制
+	// 开启后会根据服务器的限制截断过长的日志、裁剪多余的标签并拆分过大的推送
+	// 获取失败时不做任何调整
+	FetchLimits bool
 }
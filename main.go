@@ -17,6 +17,7 @@ func main() {
 		MinWaitTime: 1,             // 最少等待1秒
 		MaxWaitTime: 10,            // 最多等待10秒
 		MinLevel:    pkg.LevelInfo, // 设置最低日志级别为Info
+		MaxConnAge:  60,            // 每60秒回收一次空闲连接
 	}
 
 	// 创建并启动客户端
@@ -20,36 +20,41 @@ type LogEntry struct {
 	Level LogLevel
 }
 
-// Buffer 实现了一个线程安全的日志缓冲区
+// Buffer 实现了一个线程安全的分段日志缓冲区
 // 主要功能：
 // 1. 临时存储待发送的日志
-// 2. 支持批量操作
-// 3. 确保并发安全
+// 2. 按固定大小的段组织日志，写满的段整体交给发送方，避免大切片反复扩容和拷贝
+// 3. 发送方处理已满的段时，生产者可以继续写入下一个段
+// 4. 确保并发安全
 type Buffer struct {
-	// entries 存储所有待发送的日志条目
-	// 使用切片实现，支持动态增长
-	entries []LogEntry
+	// current 是当前正在写入的段
+	// 容量固定为size，写满后移入full
+	current []LogEntry
+
+	// full 存储已写满、等待发送的段
+	// 按写入顺序排列，先写满的段先发送
+	full [][]LogEntry
 
 	// mu 互斥锁，用于保护并发访问
 	// 确保在多个goroutine同时操作时的数据一致性
 	mu sync.Mutex
 
-	// size 表示缓冲区的目标大小
-	// 当日志数量达到此大小时，应该触发发送操作
+	// size 表示每个段的大小
+	// 当一个段写满时，应该触发发送操作
 	size int
 }
 
 // NewBuffer 创建并初始化一个新的缓冲区
 // 参数：
-//   - size: 缓冲区的目标大小，达到此大小时应触发发送
+//   - size: 每个段的大小，段写满时应触发发送
 //
 // 返回：
 //   - *Buffer: 初始化好的缓冲区实例
 func NewBuffer(size int) *Buffer {
 	return &Buffer{
-		// 预分配切片，容量设置为目标大小
-		// 这样可以减少动态扩容的次数，提高性能
-		entries: make([]LogEntry, 0, size),
+		// 预分配段，容量设置为段大小
+		// 段写满前不会发生扩容
+		current: make([]LogEntry, 0, size),
 		size:    size,
 	}
 }
@@ -60,17 +65,45 @@ func NewBuffer(size int) *Buffer {
 //   - entry: 要添加的日志条目
 //
 // 返回：
-//   - bool: 如果缓冲区达到目标大小返回true，表示应该触发发送操作
+//   - bool: 如果存在已写满的段返回true，表示应该触发发送操作
 func (b *Buffer) Add(entry LogEntry) bool {
 	// 加锁保护并发访问
 	b.mu.Lock()
 	// 确保在方法返回时解锁
 	defer b.mu.Unlock()
 
-	// 添加日志条目到切片
-	b.entries = append(b.entries, entry)
-	// 检查是否达到目标大小
-	return len(b.entries) >= b.size
+	// 添加日志条目到当前段
+	b.current = append(b.current, entry)
+	// 当前段写满后整体移交，并开始写入新的段
+	if len(b.current) >= b.size {
+		b.full = append(b.full, b.current)
+		b.current = make([]LogEntry, 0, b.size)
+	}
+	return len(b.full) > 0
+}
+
+// Next 取出一个已写满的段
+// 该方法是线程安全的，通常在Add返回true后循环调用，直到返回nil
+// 返回：
+//   - []LogEntry: 最早写满的段，没有已写满的段时返回nil
+//
+// 说明：
+//
+//	返回的段归调用方所有，缓冲区不会再修改它
+func (b *Buffer) Next() []LogEntry {
+	// 加锁保护并发访问
+	b.mu.Lock()
+	// 确保在方法返回时解锁
+	defer b.mu.Unlock()
+
+	if len(b.full) == 0 {
+		return nil
+	}
+	segment := b.full[0]
+	// 清除引用，避免已发送的段无法被回收
+	b.full[0] = nil
+	b.full = b.full[1:]
+	return segment
 }
 
 // Flush 清空缓冲区并返回所有日志条目
@@ -81,16 +114,45 @@ func (b *Buffer) Add(entry LogEntry) bool {
 // 说明：
 //
 //	调用此方法后，缓冲区会被清空，返回的切片包含所有之前的日志条目
+//	该方法需要将所有段合并为一个切片，按段发送时应使用FlushSegments
 func (b *Buffer) Flush() []LogEntry {
+	segments := b.FlushSegments()
+	if len(segments) == 1 {
+		return segments[0]
+	}
+
+	n := 0
+	for _, segment := range segments {
+		n += len(segment)
+	}
+	entries := make([]LogEntry, 0, n)
+	for _, segment := range segments {
+		entries = append(entries, segment...)
+	}
+	return entries
+}
+
+// FlushSegments 清空缓冲区并返回所有段
+// 该方法是线程安全的，通常在需要按段发送所有日志时调用
+// 返回：
+//   - [][]LogEntry: 所有待发送的段，包括未写满的当前段
+//
+// 说明：
+//
+//	调用此方法后，缓冲区会被清空，返回的段按写入顺序排列
+func (b *Buffer) FlushSegments() [][]LogEntry {
 	// 加锁保护并发访问
 	b.mu.Lock()
 	// 确保在方法返回时解锁
 	defer b.mu.Unlock()
 
-	// 保存当前的日志条目
-	entries := b.entries
-	// 创建新的空切片，预分配容量以优化性能
-	b.entries = make([]LogEntry, 0, b.size)
-	// 返回之前的日志条目
-	return entries
+	// 取出所有已写满的段
+	segments := b.full
+	b.full = nil
+	// 当前段不为空时一并取出，并创建新的段
+	if len(b.current) > 0 {
+		segments = append(segments, b.current)
+		b.current = make([]LogEntry, 0, b.size)
+	}
+	return segments
 }